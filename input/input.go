@@ -0,0 +1,191 @@
+// Package input maps physical keys, controller buttons and analog sticks
+// onto named game actions, configurable from a keybinding file instead of
+// scattering scancode checks through the game loop.
+package input
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Action is a named thing the player can do, independent of which key or
+// button currently triggers it.
+type Action string
+
+const (
+	MoveForward  Action = "move_forward"
+	MoveBackward Action = "move_backward"
+	StrafeLeft   Action = "strafe_left"
+	StrafeRight  Action = "strafe_right"
+	TurnLeft     Action = "turn_left"
+	TurnRight    Action = "turn_right"
+	TurnAround   Action = "turn_around"
+	Fire         Action = "fire"
+	Sprint       Action = "sprint"
+	Interact     Action = "interact"
+)
+
+// deadzone ignores small analog stick drift so a resting controller
+// doesn't register as held input.
+const deadzone = 0.2
+
+// Binding is everything that can trigger a single action. Any of the
+// three may be set at once, so the same action can respond to keyboard
+// and controller input side by side.
+type Binding struct {
+	Key      string `json:"key,omitempty"`      // SDL key name, e.g. "W" or "Left Shift"
+	Button   string `json:"button,omitempty"`   // SDL controller button name, e.g. "a", "leftshoulder"
+	Axis     string `json:"axis,omitempty"`     // SDL controller axis name, e.g. "leftx", "triggerright"
+	AxisSign int    `json:"axisSign,omitempty"` // which direction of Axis counts as "held": +1 or -1
+}
+
+// Bindings maps every configurable action to the input that triggers it.
+type Bindings map[Action]Binding
+
+// LoadBindings reads a JSON keybinding file mapping actions to the
+// key, button or axis that should trigger them.
+func LoadBindings(path string) (Bindings, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("input: read bindings %s: %w", path, err)
+	}
+
+	var bindings Bindings
+	if err := json.Unmarshal(data, &bindings); err != nil {
+		return nil, fmt.Errorf("input: parse bindings %s: %w", path, err)
+	}
+
+	return bindings, nil
+}
+
+// DefaultBindings is the layout the engine falls back to when no
+// keybinding file is found: WASD plus arrow keys for turning on the
+// keyboard, and the left stick, right stick and right trigger on a
+// controller.
+func DefaultBindings() Bindings {
+	return Bindings{
+		MoveForward:  {Key: "W", Axis: "lefty", AxisSign: -1},
+		MoveBackward: {Key: "S", Axis: "lefty", AxisSign: 1},
+		StrafeLeft:   {Key: "A", Axis: "leftx", AxisSign: -1},
+		StrafeRight:  {Key: "D", Axis: "leftx", AxisSign: 1},
+		TurnLeft:     {Key: "Left", Axis: "rightx", AxisSign: -1},
+		TurnRight:    {Key: "Right", Axis: "rightx", AxisSign: 1},
+		TurnAround:   {Key: "Q"},
+		Sprint:       {Key: "Left Shift", Button: "leftshoulder"},
+		Fire:         {Axis: "triggerright", AxisSign: 1},
+		Interact:     {Key: "E", Button: "a"},
+	}
+}
+
+// Manager tracks the live state of every bound action from the keyboard
+// and, if one is attached, a game controller.
+type Manager struct {
+	bindings Bindings
+
+	keyActions    map[sdl.Scancode]Action
+	buttonActions map[sdl.GameControllerButton]Action
+
+	keys        []uint8
+	buttons     map[sdl.GameControllerButton]bool
+	axes        map[sdl.GameControllerAxis]int16
+	justPressed map[Action]bool
+}
+
+// NewManager builds a Manager from bindings, ready to Poll.
+func NewManager(bindings Bindings) *Manager {
+	m := &Manager{
+		bindings:      bindings,
+		keyActions:    make(map[sdl.Scancode]Action),
+		buttonActions: make(map[sdl.GameControllerButton]Action),
+		buttons:       make(map[sdl.GameControllerButton]bool),
+		axes:          make(map[sdl.GameControllerAxis]int16),
+	}
+
+	for action, b := range bindings {
+		if b.Key != "" {
+			m.keyActions[sdl.GetScancodeFromName(b.Key)] = action
+		}
+		if b.Button != "" {
+			m.buttonActions[sdl.GameControllerGetButtonFromString(b.Button)] = action
+		}
+	}
+
+	return m
+}
+
+// Poll updates the Manager's state from this frame's SDL events. It
+// should be called once per frame, with every event the game loop saw.
+func (m *Manager) Poll(events []sdl.Event) {
+	m.keys = sdl.GetKeyboardState()
+	m.justPressed = make(map[Action]bool)
+
+	for _, evt := range events {
+		switch e := evt.(type) {
+		case *sdl.KeyboardEvent:
+			if e.State != sdl.PRESSED || e.Repeat != 0 {
+				continue
+			}
+			if action, ok := m.keyActions[e.Keysym.Scancode]; ok {
+				m.justPressed[action] = true
+			}
+
+		case *sdl.ControllerButtonEvent:
+			button := sdl.GameControllerButton(e.Button)
+			pressed := e.State == sdl.PRESSED
+			m.buttons[button] = pressed
+			if pressed {
+				if action, ok := m.buttonActions[button]; ok {
+					m.justPressed[action] = true
+				}
+			}
+
+		case *sdl.ControllerAxisEvent:
+			m.axes[sdl.GameControllerAxis(e.Axis)] = e.Value
+		}
+	}
+}
+
+// Value reports how strongly action is currently held, from 0 (not held)
+// to 1 (fully held), combining whichever of its key, button and axis
+// bindings are present.
+func (m *Manager) Value(action Action) float64 {
+	b, ok := m.bindings[action]
+	if !ok {
+		return 0
+	}
+
+	if b.Key != "" && m.keys != nil && m.keys[sdl.GetScancodeFromName(b.Key)] == 1 {
+		return 1
+	}
+
+	if b.Button != "" && m.buttons[sdl.GameControllerGetButtonFromString(b.Button)] {
+		return 1
+	}
+
+	if b.Axis != "" {
+		raw := float64(m.axes[sdl.GameControllerGetAxisFromString(b.Axis)]) / 32767
+		if b.AxisSign < 0 {
+			raw = -raw
+		}
+		if raw > deadzone {
+			return math.Min(raw, 1)
+		}
+	}
+
+	return 0
+}
+
+// Down reports whether action is currently held at all.
+func (m *Manager) Down(action Action) bool {
+	return m.Value(action) > 0
+}
+
+// JustPressed reports whether action's key or button transitioned from
+// released to pressed on the events passed to the most recent Poll call.
+func (m *Manager) JustPressed(action Action) bool {
+	return m.justPressed[action]
+}
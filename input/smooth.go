@@ -0,0 +1,54 @@
+package input
+
+import "math"
+
+// SmoothAction eases a value towards a target over time, at most Rate
+// units per Step call. Any bound action that wants an eased change —
+// the player's 180-degree turnaround, a future camera lean, a weapon
+// recoil kick — can drive one of these instead of hand-rolling its own
+// countdown state.
+type SmoothAction struct {
+	Rate float64
+
+	current float64
+	target  float64
+}
+
+// NewSmoothAction creates a SmoothAction that moves towards its target
+// at up to rate units per Step call.
+func NewSmoothAction(rate float64) *SmoothAction {
+	return &SmoothAction{Rate: rate}
+}
+
+// Request sets the value the action should ease towards.
+func (s *SmoothAction) Request(target float64) {
+	s.target = target
+}
+
+// Nudge adds delta to the current target, useful for relative requests
+// like "turn another half-circle from wherever we're headed".
+func (s *SmoothAction) Nudge(delta float64) {
+	s.target += delta
+}
+
+// Value returns the action's current eased value.
+func (s *SmoothAction) Value() float64 {
+	return s.current
+}
+
+// Step advances the current value towards the target by at most Rate,
+// and returns the delta applied this call.
+func (s *SmoothAction) Step() float64 {
+	diff := s.target - s.current
+	if diff == 0 {
+		return 0
+	}
+
+	step := math.Copysign(s.Rate, diff)
+	if math.Abs(diff) < math.Abs(step) {
+		step = diff
+	}
+
+	s.current += step
+	return step
+}
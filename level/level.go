@@ -0,0 +1,291 @@
+// Package level loads game levels authored in Tiled (tmx/tsx) into the
+// in-memory structures the renderer and game loop operate on, so new
+// levels can be built in the Tiled map editor instead of as Go string
+// literals.
+package level
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Tile identifies a tile by its global Tiled GID. Zero means no tile is
+// present in that cell.
+type Tile int
+
+// Empty is the GID Tiled uses for an unset cell.
+const Empty Tile = 0
+
+// Map is a parsed level: independent floor, ceiling and wall layers
+// addressed as [y][x], plus the GID -> texture name lookup gathered from
+// the level's tilesets.
+type Map struct {
+	Floor   [][]Tile
+	Ceiling [][]Tile
+	Walls   [][]Tile
+	Texture map[Tile]string
+}
+
+// Width returns the map's width in tiles.
+func (m Map) Width() int {
+	if len(m.Walls) == 0 {
+		return 0
+	}
+	return len(m.Walls[0])
+}
+
+// Height returns the map's height in tiles.
+func (m Map) Height() int {
+	return len(m.Walls)
+}
+
+// IsWall reports whether (x, y) is occupied by a wall tile. Cells outside
+// the map bounds count as walls, so callers don't need a separate bounds
+// check.
+func (m Map) IsWall(x, y int) bool {
+	if y < 0 || y >= len(m.Walls) || x < 0 || x >= len(m.Walls[y]) {
+		return true
+	}
+	return m.Walls[y][x] != Empty
+}
+
+// TextureFor returns the texture name a tileset declared for the given
+// GID, as parsed out of its TSX.
+func (m Map) TextureFor(t Tile) (string, bool) {
+	name, ok := m.Texture[t]
+	return name, ok
+}
+
+// FloorAt returns the floor tile at (x, y), or Empty if the level has no
+// floor layer or (x, y) falls outside it.
+func (m Map) FloorAt(x, y int) Tile {
+	if y < 0 || y >= len(m.Floor) || x < 0 || x >= len(m.Floor[y]) {
+		return Empty
+	}
+	return m.Floor[y][x]
+}
+
+// CeilingAt returns the ceiling tile at (x, y), or Empty if the level has
+// no ceiling layer or (x, y) falls outside it.
+func (m Map) CeilingAt(x, y int) Tile {
+	if y < 0 || y >= len(m.Ceiling) || x < 0 || x >= len(m.Ceiling[y]) {
+		return Empty
+	}
+	return m.Ceiling[y][x]
+}
+
+// Entity is a single object-layer entry: a sprite to be placed in the
+// world.
+type Entity struct {
+	X, Y, Width float64
+	Texture     string
+}
+
+// PlayerSpawn is the position and facing angle the player starts at, read
+// from the object named "player" in the entity layer.
+type PlayerSpawn struct {
+	X, Y, Angle float64
+}
+
+// LoadLevel parses the TMX file at path into a Map, the entities placed on
+// its object layer, and the player's spawn point.
+func LoadLevel(path string) (Map, []*Entity, PlayerSpawn, error) {
+	var m Map
+
+	f, err := os.Open(path)
+	if err != nil {
+		return m, nil, PlayerSpawn{}, fmt.Errorf("level: open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var doc tmxMap
+	if err := xml.NewDecoder(f).Decode(&doc); err != nil {
+		return m, nil, PlayerSpawn{}, fmt.Errorf("level: parse %s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+
+	textures, err := loadTilesets(dir, doc.Tilesets)
+	if err != nil {
+		return m, nil, PlayerSpawn{}, err
+	}
+	m.Texture = textures
+
+	for _, l := range doc.Layers {
+		grid, err := l.grid()
+		if err != nil {
+			return m, nil, PlayerSpawn{}, fmt.Errorf("level: layer %q: %w", l.Name, err)
+		}
+
+		switch l.Name {
+		case "floor":
+			m.Floor = grid
+		case "ceiling":
+			m.Ceiling = grid
+		case "walls":
+			m.Walls = grid
+		default:
+			return m, nil, PlayerSpawn{}, fmt.Errorf("level: unrecognised layer %q", l.Name)
+		}
+	}
+
+	entities, spawn, err := loadEntities(doc, float64(doc.TileWidth), float64(doc.TileHeight))
+	if err != nil {
+		return m, nil, PlayerSpawn{}, err
+	}
+
+	return m, entities, spawn, nil
+}
+
+func loadTilesets(dir string, refs []tmxTilesetRef) (map[Tile]string, error) {
+	textures := make(map[Tile]string)
+
+	for _, ref := range refs {
+		f, err := os.Open(filepath.Join(dir, ref.Source))
+		if err != nil {
+			return nil, fmt.Errorf("level: open tileset %s: %w", ref.Source, err)
+		}
+
+		var ts tsxTileset
+		err = xml.NewDecoder(f).Decode(&ts)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("level: parse tileset %s: %w", ref.Source, err)
+		}
+
+		for _, tile := range ts.Tiles {
+			name := strings.TrimSuffix(filepath.Base(tile.Image.Source), filepath.Ext(tile.Image.Source))
+			textures[Tile(ref.FirstGID+tile.ID)] = name
+		}
+	}
+
+	return textures, nil
+}
+
+func loadEntities(doc tmxMap, tileWidth, tileHeight float64) ([]*Entity, PlayerSpawn, error) {
+	var (
+		entities []*Entity
+		spawn    PlayerSpawn
+		gotSpawn bool
+	)
+
+	for _, group := range doc.ObjectGroups {
+		for _, obj := range group.Objects {
+			x := obj.X / tileWidth
+			y := obj.Y / tileHeight
+
+			if obj.Name == "player" {
+				spawn = PlayerSpawn{X: x, Y: y, Angle: obj.Rotation * math.Pi / 180}
+				gotSpawn = true
+				continue
+			}
+
+			entities = append(entities, &Entity{
+				X:     x,
+				Y:     y,
+				Width: obj.Width / tileWidth,
+				Texture: func() string {
+					if obj.Type != "" {
+						return obj.Type
+					}
+					return obj.Name
+				}(),
+			})
+		}
+	}
+
+	if !gotSpawn {
+		return nil, PlayerSpawn{}, fmt.Errorf("level: no object named %q found in entity layer", "player")
+	}
+
+	return entities, spawn, nil
+}
+
+// The tmx* and tsx* types below mirror just enough of the Tiled TMX/TSX XML
+// schema to load the layers, tilesets and objects this engine cares about.
+
+type tmxMap struct {
+	XMLName      xml.Name         `xml:"map"`
+	TileWidth    int              `xml:"tilewidth,attr"`
+	TileHeight   int              `xml:"tileheight,attr"`
+	Tilesets     []tmxTilesetRef  `xml:"tileset"`
+	Layers       []tmxLayer       `xml:"layer"`
+	ObjectGroups []tmxObjectGroup `xml:"objectgroup"`
+}
+
+type tmxTilesetRef struct {
+	FirstGID int    `xml:"firstgid,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+type tmxLayer struct {
+	Name   string  `xml:"name,attr"`
+	Width  int     `xml:"width,attr"`
+	Height int     `xml:"height,attr"`
+	Data   tmxData `xml:"data"`
+}
+
+type tmxData struct {
+	Encoding string `xml:"encoding,attr"`
+	CharData string `xml:",chardata"`
+}
+
+func (l tmxLayer) grid() ([][]Tile, error) {
+	if l.Data.Encoding != "csv" {
+		return nil, fmt.Errorf("unsupported layer encoding %q, only csv is supported", l.Data.Encoding)
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(strings.TrimSpace(l.Data.CharData))).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	grid := make([][]Tile, len(rows))
+	for y, row := range rows {
+		grid[y] = make([]Tile, len(row))
+		for x, cell := range row {
+			gid, err := strconv.Atoi(strings.TrimSpace(cell))
+			if err != nil {
+				return nil, fmt.Errorf("tile (%d, %d): %w", x, y, err)
+			}
+			grid[y][x] = Tile(gid)
+		}
+	}
+
+	return grid, nil
+}
+
+type tmxObjectGroup struct {
+	Name    string      `xml:"name,attr"`
+	Objects []tmxObject `xml:"object"`
+}
+
+type tmxObject struct {
+	Name     string  `xml:"name,attr"`
+	Type     string  `xml:"type,attr"`
+	X        float64 `xml:"x,attr"`
+	Y        float64 `xml:"y,attr"`
+	Width    float64 `xml:"width,attr"`
+	Height   float64 `xml:"height,attr"`
+	Rotation float64 `xml:"rotation,attr"`
+}
+
+type tsxTileset struct {
+	XMLName xml.Name  `xml:"tileset"`
+	Tiles   []tsxTile `xml:"tile"`
+}
+
+type tsxTile struct {
+	ID    int      `xml:"id,attr"`
+	Image tsxImage `xml:"image"`
+}
+
+type tsxImage struct {
+	Source string `xml:"source,attr"`
+}
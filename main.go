@@ -4,40 +4,80 @@ import (
 	"fmt"
 	"math"
 	"runtime"
-	"strings"
+	"sort"
+	"time"
 
 	"github.com/veandco/go-sdl2/img"
 
 	"github.com/veandco/go-sdl2/sdl"
-)
 
-const (
-	focalLength float64 = 0.8
+	"github.com/zac-garby/fps/input"
+	"github.com/zac-garby/fps/level"
+	"github.com/zac-garby/fps/pathing"
+	"github.com/zac-garby/fps/render"
+	"github.com/zac-garby/fps/texture"
 )
 
-var (
-	textures map[string]*sdl.Texture = make(map[string]*sdl.Texture)
-)
-
-// A Tile is used to store the type of a particular tile in the level.
-type Tile = int
-
 const (
-	empty Tile = 0
-	wall1 Tile = 1
-	wall2 Tile = 2
+	focalLength           float64 = 0.8
+	levelPath                     = "assets/levels/sample.tmx"
+	bindingsPath                  = "assets/bindings.json"
+	textureScavengePeriod         = 5 * time.Second
+	turnAroundRate                = 0.013
 )
 
-// A Map stores the set of tiles which make up a game level.
-type Map = [][]Tile
+var textures *texture.Manager
 
-// An Entity represents a sprite to be drawn in the world.
+// An Entity represents a sprite to be drawn in the world, named by the
+// texture it should be drawn with.
 type Entity struct {
 	X, Y, Width float64
-	Texture     *sdl.Texture
+	Texture     string
+	AI          *AI
+}
+
+// AI gives an entity a path toward a target and walks it along, one step
+// per frame, re-planning once it runs out of waypoints. Entities without
+// AI are static.
+type AI struct {
+	Grid *pathing.PathGrid
+	path []pathing.Vec2
+}
+
+// Chase plans a's path to (tx, ty) if it doesn't have one and steps the
+// entity towards the next waypoint. The path isn't re-planned while it's
+// still being walked, so if (tx, ty) moves mid-path the entity keeps
+// heading for the stale destination until the current path runs out.
+func (a *AI) Chase(e *Entity, tx, ty float64) {
+	if len(a.path) == 0 {
+		path, _, found := a.Grid.FindPath(e.X, e.Y, tx, ty)
+		if !found {
+			return
+		}
+		a.path = path
+	}
+
+	next := a.path[0]
+	dx, dy := next.X-e.X, next.Y-e.Y
+	dist := math.Hypot(dx, dy)
+
+	const (
+		speed       = 0.01
+		arriveRange = 0.05
+	)
+
+	if dist < arriveRange {
+		a.path = a.path[1:]
+		return
+	}
+
+	e.X += speed * dx / dist
+	e.Y += speed * dy / dist
 }
 
-func render(renderer *sdl.Renderer, level Map, entities []*Entity, xs, ys, angle float64) {
+// renderScene rays-casts one frame of the level and queues the resulting
+// wall slices for the render thread to draw.
+func renderScene(renderer *sdl.Renderer, lvl level.Map, entities []*Entity, xs, ys, angle float64) {
 	w, h, err := renderer.GetOutputSize()
 	if err != nil {
 		panic(err)
@@ -50,6 +90,14 @@ func render(renderer *sdl.Renderer, level Map, entities []*Entity, xs, ys, angle
 		depthMap = make([]float64, w)
 	)
 
+	// The floor and ceiling under the player stand in for whatever's
+	// actually underfoot at each column's hit point: the renderer only
+	// raycasts walls, so there's no per-column floor/ceiling cell to
+	// sample, and one flat-shaded swatch per frame is enough to make
+	// those layers visible instead of the bare background color.
+	floorName, floorTex, haveFloor := textureForOptional(lvl, lvl.FloorAt(int(xs), int(ys)))
+	ceilName, ceilTex, haveCeil := textureForOptional(lvl, lvl.CeilingAt(int(xs), int(ys)))
+
 	for sweep := 0.0; sweep < 1; sweep += gap / width {
 		var (
 			screenX         = int32(sweep * width)
@@ -62,26 +110,25 @@ func render(renderer *sdl.Renderer, level Map, entities []*Entity, xs, ys, angle
 			yd              = tempyd*cos + tempxd*sin
 			closestDistance = math.Inf(1)
 			closestMu       = math.NaN()
-			closestTile     Tile
+			closestTile     level.Tile
+			closestSide     int
 		)
 
-		for y := 0; y < len(level); y++ {
-			row := level[y]
-			for x := 0; x < len(row); x++ {
-				if row[x] == empty {
-					continue
-				}
-
-				dist, mu, hit := rayBox(xs, ys, xd, yd, float64(x), float64(y))
-				if hit && dist < closestDistance {
-					closestDistance = dist
-					closestMu = mu
-					closestTile = row[x]
-				}
-			}
+		if dist, mu, mapX, mapY, side, hit := castRay(lvl, xs, ys, xd, yd); hit {
+			closestDistance = dist
+			closestMu = mu
+			closestTile = lvl.Walls[mapY][mapX]
+			closestSide = side
 		}
 
-		depthMap[screenX] = closestDistance
+		for i := screenX; i < screenX+int32(gap) && i < int32(w); i++ {
+			depthMap[i] = closestDistance
+		}
+
+		var (
+			top    = int32(height / 2)
+			bottom = int32(height / 2)
+		)
 
 		if !math.IsInf(closestDistance, 1) {
 			sliceHeight := int32(
@@ -94,8 +141,9 @@ func render(renderer *sdl.Renderer, level Map, entities []*Entity, xs, ys, angle
 				W: int32(gap),
 				H: sliceHeight,
 			}
+			top, bottom = dest.Y, dest.Y+dest.H
 
-			tex := textureFor(closestTile)
+			name, tex := textureFor(lvl, closestTile)
 
 			_, _, tw, th, _ := tex.Query()
 
@@ -106,125 +154,180 @@ func render(renderer *sdl.Renderer, level Map, entities []*Entity, xs, ys, angle
 				H: th,
 			}
 
-			var (
-				torchMul   = math.Pow(4*sweep*(1-sweep), 1.4)
-				brightness = uint8(math.Min(255*torchMul/math.Pow(closestDistance, 1.5), 255))
-			)
-			if closestDistance < 1 {
-				brightness = 255
+			brightness := columnBrightness(sweep, closestDistance)
+			// Y-side faces are darkened a shade relative to X-side faces,
+			// a cheap fake-lighting cue that makes adjacent wall faces
+			// read as distinct surfaces instead of a flat wash of color.
+			if closestSide == 1 {
+				brightness = uint8(float64(brightness) * 0.8)
 			}
 
-			tex.SetColorMod(brightness, brightness, brightness)
-			renderer.Copy(tex, src, dest)
+			render.Copy(textures, name, tex, src, dest, brightness)
 		}
-	}
-}
 
-func rayLine(xs, ys, xd, yd, xc1, yc1, xc2, yc2 float64) (float64, float64, bool) {
-	lambda := (xs*yc1 + xc1*yc2 + xc2*ys - xs*yc2 - xc1*ys - xc2*yc1) / (xd*yc1 + xc2*yd - xd*yc2 - xc1*yd)
-	if lambda <= 0 {
-		return 0, 0, false
-	}
+		brightness := columnBrightness(sweep, closestDistance)
 
-	mu := (xd*yc1 + xs*yd - xc1*yd - xd*ys) / (xd*yc2 + xc1*yd - xd*yc1 - xc2*yd)
-	if mu < 0 || mu > 1 {
-		return 0, 0, false
+		if haveCeil {
+			fillBand(ceilName, ceilTex, screenX, 0, top, gap, brightness)
+		}
+		if haveFloor {
+			fillBand(floorName, floorTex, screenX, bottom, int32(height), gap, brightness)
+		}
 	}
 
-	dist := lambda / math.Sqrt(xd*xd+yd*yd)
-
-	return dist, mu, true
+	drawSprites(entities, xs, ys, angle, width, height, depthMap)
 }
 
-func rayBox(xs, ys, xd, yd, cx, cy float64) (float64, float64, bool) {
-	sides := [][4]float64{
-		{cx, cy, cx + 1, cy},
-		{cx, cy, cx, cy + 1},
-		{cx, cy - 1, cx + 1, cy - 1},
-		{cx - 1, cy, cx - 1, cy + 1},
-	}
+// drawSprites billboards each entity towards the camera, sorted
+// back-to-front, and queues the visible stripes of each one that aren't
+// hidden behind a nearer wall in depthMap.
+func drawSprites(entities []*Entity, xs, ys, angle, width, height float64, depthMap []float64) {
+	sort.Slice(entities, func(i, j int) bool {
+		di := (entities[i].X-xs)*(entities[i].X-xs) + (entities[i].Y-ys)*(entities[i].Y-ys)
+		dj := (entities[j].X-xs)*(entities[j].X-xs) + (entities[j].Y-ys)*(entities[j].Y-ys)
+		return di > dj
+	})
 
 	var (
-		closest   = 0.0
-		closestMu = 0.0
-		didHit    = false
+		dirX, dirY     = -math.Sin(angle), math.Cos(angle)
+		planeX, planeY = math.Cos(angle) / (2 * focalLength), math.Sin(angle) / (2 * focalLength)
+		invDet         = 1 / (planeX*dirY - dirX*planeY)
 	)
 
-	for _, side := range sides {
-		dist, mu, hit := rayLine(xs, ys, xd, yd, side[0], side[1], side[2], side[3])
-		if !hit {
+	for _, e := range entities {
+		if e.Texture == "" {
 			continue
 		}
 
-		if !didHit || dist < closest {
-			closest = dist
-			closestMu = mu
-			didHit = true
+		rx, ry := e.X-xs, e.Y-ys
+
+		transformX := invDet * (dirY*rx - dirX*ry)
+		transformY := invDet * (-planeY*rx + planeX*ry)
+
+		if transformY <= 0 {
+			continue // behind the camera
 		}
-	}
 
-	return closest, closestMu, didHit
+		spriteScreenX := (width / 2) * (1 + transformX/transformY)
+		spriteHeight := math.Abs(height / transformY)
+		spriteWidth := spriteHeight * e.Width
+
+		drawStartX := int(spriteScreenX - spriteWidth/2)
+		drawEndX := int(spriteScreenX + spriteWidth/2)
+		drawStartY := int32(height/2 - spriteHeight/2)
+
+		tex, err := textures.Get(e.Texture)
+		if err != nil {
+			panic(err)
+		}
+
+		_, _, tw, th, _ := tex.Query()
+
+		for stripe := drawStartX; stripe < drawEndX; stripe++ {
+			if stripe < 0 || stripe >= int(width) || transformY >= depthMap[stripe] {
+				continue
+			}
+
+			texX := int32((float64(stripe) - (spriteScreenX - spriteWidth/2)) * float64(tw) / spriteWidth)
+			if texX < 0 || texX >= tw {
+				continue
+			}
+
+			sweep := float64(stripe) / width
+			torchMul := math.Pow(4*sweep*(1-sweep), 1.4)
+			brightness := uint8(math.Min(255*torchMul/math.Pow(transformY, 1.5), 255))
+			if transformY < 1 {
+				brightness = 255
+			}
+
+			src := &sdl.Rect{X: texX, Y: 0, W: 1, H: th}
+			dest := &sdl.Rect{X: int32(stripe), Y: drawStartY, W: 1, H: int32(spriteHeight)}
+
+			render.Copy(textures, e.Texture, tex, src, dest, brightness)
+		}
+	}
 }
 
-func mapFromString(str string) Map {
+// castRay walks the grid DDA traversal from (xs, ys) along the unit
+// direction (xd, yd) until it finds a wall tile or leaves the map. It
+// returns the perpendicular hit distance, the texture-space mu across
+// the hit wall face, the map cell that was hit, which side of that cell
+// was hit (0 = an X side, a vertical wall face; 1 = a Y side), and
+// whether anything was hit at all.
+func castRay(lvl level.Map, xs, ys, xd, yd float64) (dist, mu float64, mapX, mapY, side int, hit bool) {
+	mapX, mapY = int(xs), int(ys)
+
 	var (
-		lines = strings.Split(str, "\n")
-		m     = make(Map, len(lines))
+		deltaDistX = math.Abs(1 / xd)
+		deltaDistY = math.Abs(1 / yd)
+		sideDistX  float64
+		sideDistY  float64
+		stepX      int
+		stepY      int
 	)
 
-	for j, line := range strings.Split(str, "\n") {
-		m[j] = make([]Tile, len(line))
-
-		for i, char := range line {
-			var t Tile
-			switch char {
-			case '1':
-				t = wall1
-			case '2':
-				t = wall2
-			default:
-				t = empty
-			}
+	if xd < 0 {
+		stepX = -1
+		sideDistX = (xs - float64(mapX)) * deltaDistX
+	} else {
+		stepX = 1
+		sideDistX = (float64(mapX) + 1 - xs) * deltaDistX
+	}
+
+	if yd < 0 {
+		stepY = -1
+		sideDistY = (ys - float64(mapY)) * deltaDistY
+	} else {
+		stepY = 1
+		sideDistY = (float64(mapY) + 1 - ys) * deltaDistY
+	}
 
-			m[j][i] = t
+	for {
+		if sideDistX < sideDistY {
+			sideDistX += deltaDistX
+			mapX += stepX
+			side = 0
+		} else {
+			sideDistY += deltaDistY
+			mapY += stepY
+			side = 1
+		}
+
+		if mapX < 0 || mapX >= lvl.Width() || mapY < 0 || mapY >= lvl.Height() {
+			return 0, 0, 0, 0, 0, false
+		}
+
+		if lvl.IsWall(mapX, mapY) {
+			break
+		}
+	}
+
+	if side == 0 {
+		dist = sideDistX - deltaDistX
+		mu = ys + dist*yd
+		mu -= math.Floor(mu)
+		if xd > 0 {
+			mu = 1 - mu
+		}
+	} else {
+		dist = sideDistY - deltaDistY
+		mu = xs + dist*xd
+		mu -= math.Floor(mu)
+		if yd < 0 {
+			mu = 1 - mu
 		}
 	}
 
-	return m
+	return dist, mu, mapX, mapY, side, true
 }
 
 func main() {
 	runtime.LockOSThread()
 
-	level := mapFromString(`
- 111111111111111111111111111111111
- 1                               1
- 1                               2
- 1                               1
- 1      11111111111111111111111111
- 1                               1
- 1                               1
- 1                               1
- 1                               1
- 1                               1
- 1                               1
- 1                               1
- 1                               1
- 1                               1
- 1                               1
- 1                               1
- 1                               1
- 111111111111111111111111111111111`,
-	)
-
-	entities := []*Entity{}
-
-	var (
-		x, y     = 2.5, 2.0
-		angle    = 0.0
-		toTurn   = 0.0
-		bobTimer = 0.0
-	)
+	lvl, lvlEntities, spawn, err := level.LoadLevel(levelPath)
+	if err != nil {
+		panic(err)
+	}
 
 	var version sdl.Version
 	sdl.GetVersion(&version)
@@ -247,118 +350,198 @@ func main() {
 
 	window.SetTitle("fps")
 
-	loadTextures(renderer)
+	if sdl.NumJoysticks() > 0 {
+		if controller := sdl.GameControllerOpen(0); controller != nil {
+			defer controller.Close()
+		}
+	}
 
-	running := true
-	for running {
-		for evt := sdl.PollEvent(); evt != nil; evt = sdl.PollEvent() {
-			switch s := evt.(type) {
-			case *sdl.QuitEvent:
-				running = false
-				break
-
-			case *sdl.KeyboardEvent:
-				if s.State == sdl.PRESSED && s.Keysym.Scancode == sdl.SCANCODE_Q {
-					toTurn = math.Pi
-				}
-			}
+	bindings, err := input.LoadBindings(bindingsPath)
+	if err != nil {
+		bindings = input.DefaultBindings()
+	}
+	inputMgr := input.NewManager(bindings)
+
+	render.Init(renderer)
+
+	textures = texture.NewManager(renderer, render.Queue)
+	textures.Scavenger(textureScavengePeriod)
+
+	if _, err := textures.Load("shotgun"); err != nil {
+		panic(err)
+	}
+
+	pathGrid := pathing.BuildPathGrid(lvl)
+
+	entities := make([]*Entity, len(lvlEntities))
+	for i, e := range lvlEntities {
+		ent := &Entity{X: e.X, Y: e.Y, Width: e.Width, Texture: e.Texture}
+		if e.Texture == "enemy" {
+			ent.AI = &AI{Grid: pathGrid}
 		}
+		entities[i] = ent
+	}
 
-		if toTurn > 0.005 {
-			angle += 0.013
-			toTurn -= 0.013
+	done := make(chan struct{})
+	go simulate(renderer, lvl, entities, spawn, inputMgr, done)
+
+	// The renderer was created on this, the OS-locked thread, so all of
+	// its Copy/Present calls must be flushed here too.
+	for {
+		select {
+		case <-done:
+			return
+		default:
+			render.Purge()
 		}
+	}
+}
 
-		keys := sdl.GetKeyboardState()
-		if keys[sdl.SCANCODE_LEFT] == 1 {
-			angle += 0.002
+// simulate runs input, movement and AI on its own goroutine, queuing draw
+// commands for the render thread instead of touching the renderer
+// directly. It closes done and returns once the window is asked to quit.
+func simulate(renderer *sdl.Renderer, lvl level.Map, entities []*Entity, spawn level.PlayerSpawn, im *input.Manager, done chan struct{}) {
+	defer close(done)
+
+	var (
+		x, y       = spawn.X, spawn.Y
+		angle      = spawn.Angle
+		turnAround = input.NewSmoothAction(turnAroundRate)
+		bobTimer   = 0.0
+	)
+
+	for {
+		var events []sdl.Event
+		for evt := sdl.PollEvent(); evt != nil; evt = sdl.PollEvent() {
+			if _, ok := evt.(*sdl.QuitEvent); ok {
+				return
+			}
+			events = append(events, evt)
 		}
+		im.Poll(events)
 
-		if keys[sdl.SCANCODE_RIGHT] == 1 {
-			angle -= 0.002
+		if im.JustPressed(input.TurnAround) {
+			turnAround.Nudge(math.Pi)
 		}
+		angle += turnAround.Step()
 
-		moving := false
+		angle += 0.002 * im.Value(input.TurnLeft)
+		angle -= 0.002 * im.Value(input.TurnRight)
+
+		var (
+			forward = im.Value(input.MoveForward) - im.Value(input.MoveBackward)
+			strafe  = im.Value(input.StrafeRight) - im.Value(input.StrafeLeft)
+			moving  = forward != 0 || strafe != 0
+		)
 
 		speed := 0.002
-		if keys[sdl.SCANCODE_LSHIFT] == 1 {
+		if im.Down(input.Sprint) {
 			speed = 0.005
-			if keys[sdl.SCANCODE_UP] == 1 ||
-				keys[sdl.SCANCODE_W] == 1 ||
-				keys[sdl.SCANCODE_DOWN] == 1 ||
-				keys[sdl.SCANCODE_S] == 1 ||
-				keys[sdl.SCANCODE_A] == 1 ||
-				keys[sdl.SCANCODE_D] == 1 {
+			if moving {
 				bobTimer += 0.01
 			}
 		}
 
-		if keys[sdl.SCANCODE_UP] == 1 || keys[sdl.SCANCODE_W] == 1 {
-			y -= speed * math.Cos(angle)
-			x += speed * math.Sin(angle)
-			moving = true
-		}
-		if keys[sdl.SCANCODE_DOWN] == 1 || keys[sdl.SCANCODE_S] == 1 {
-			y += speed * math.Cos(angle)
-			x -= speed * math.Sin(angle)
-			moving = true
-		}
-
-		if keys[sdl.SCANCODE_D] == 1 {
-			y += speed * math.Cos(angle+math.Pi/2)
-			x -= speed * math.Sin(angle+math.Pi/2)
-			moving = true
-		}
-		if keys[sdl.SCANCODE_A] == 1 {
-			y -= speed * math.Cos(angle+math.Pi/2)
-			x += speed * math.Sin(angle+math.Pi/2)
-			moving = true
-		}
+		y += speed * (-forward*math.Cos(angle) + strafe*math.Cos(angle+math.Pi/2))
+		x += speed * (forward*math.Sin(angle) - strafe*math.Sin(angle+math.Pi/2))
 
 		if moving {
 			bobTimer += 0.016
 		}
 
-		renderer.SetDrawColor(0, 0, 0, 255)
-		renderer.Clear()
+		for _, e := range entities {
+			if e.AI != nil {
+				e.AI.Chase(e, x, y)
+			}
+		}
+
+		render.Queue(func(r *sdl.Renderer) {
+			r.SetDrawColor(0, 0, 0, 255)
+			r.Clear()
+		})
 
-		render(renderer, level, entities, x, y, angle)
+		renderScene(renderer, lvl, entities, x, y, angle)
+
+		shotgun, err := textures.Get("shotgun")
+		if err != nil {
+			panic(err)
+		}
 
 		var (
 			boby = int32(math.Abs(math.Sin(bobTimer*0.3) * 32))
 			bobx = int32(math.Cos(bobTimer*0.3) * 20)
 		)
 
-		renderer.Copy(textures["shotgun"], nil, &sdl.Rect{X: 512 - 384 + bobx, Y: 512 - 384 + boby, W: 384, H: 384})
+		render.Copy(textures, "shotgun", shotgun, nil, &sdl.Rect{X: 512 - 384 + bobx, Y: 512 - 384 + boby, W: 384, H: 384}, 255)
 
-		renderer.Present()
+		render.Queue(func(r *sdl.Renderer) {
+			r.Present()
+		})
 	}
 }
 
-func loadTextures(renderer *sdl.Renderer) {
-	toLoad := []string{
-		"shotgun",
-		"wall",
-		"wall-2",
+func textureFor(lvl level.Map, t level.Tile) (string, *sdl.Texture) {
+	name, ok := lvl.TextureFor(t)
+	if !ok {
+		panic(fmt.Sprintf("undefined tile when getting texture: %d", t))
 	}
 
-	for _, name := range toLoad {
-		tex, err := img.LoadTexture(renderer, fmt.Sprintf("assets/%s.png", name))
-		if err != nil {
-			panic(err)
-		}
+	tex, err := textures.Get(name)
+	if err != nil {
+		panic(err)
+	}
+
+	return name, tex
+}
+
+// textureForOptional resolves t to a texture like textureFor, but treats
+// an empty tile or one the level's tilesets don't define as "nothing to
+// draw" instead of panicking. Floor and ceiling layers are optional, so a
+// level that doesn't author one should just leave it undrawn.
+func textureForOptional(lvl level.Map, t level.Tile) (string, *sdl.Texture, bool) {
+	if t == level.Empty {
+		return "", nil, false
+	}
+
+	name, ok := lvl.TextureFor(t)
+	if !ok {
+		return "", nil, false
+	}
+
+	tex, err := textures.Get(name)
+	if err != nil {
+		panic(err)
+	}
+
+	return name, tex, true
+}
 
-		textures[name] = tex
+// columnBrightness computes the torch/distance falloff used to
+// fake-light a single raycast column at dist, shared by the wall slice
+// and the floor/ceiling bands drawn above and below it.
+func columnBrightness(sweep, dist float64) uint8 {
+	torchMul := math.Pow(4*sweep*(1-sweep), 1.4)
+	brightness := uint8(math.Min(255*torchMul/math.Pow(dist, 1.5), 255))
+	if dist < 1 {
+		brightness = 255
 	}
+	return brightness
 }
 
-func textureFor(t Tile) *sdl.Texture {
-	switch t {
-	case wall1:
-		return textures["wall"]
-	case wall2:
-		return textures["wall-2"]
-	default:
-		panic(fmt.Sprintf("undefined tile when getting texture: %d", t))
+// fillBand queues a single flat-shaded swatch, sampled from the middle of
+// tex, stretched across the column from top to bottom. It's how the floor
+// and ceiling layers are drawn: the renderer only raycasts walls, so
+// there's no per-pixel floor/ceiling hit to texture-map properly, but a
+// shaded swatch still makes those layers read as distinct surfaces
+// instead of leaving the background color showing through.
+func fillBand(name string, tex *sdl.Texture, screenX, top, bottom int32, gap float64, brightness uint8) {
+	if bottom <= top {
+		return
 	}
+
+	_, _, tw, th, _ := tex.Query()
+	src := &sdl.Rect{X: tw / 2, Y: th / 2, W: 1, H: 1}
+	dest := &sdl.Rect{X: screenX, Y: top, W: int32(gap), H: bottom - top}
+
+	render.Copy(textures, name, tex, src, dest, brightness)
 }
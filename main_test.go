@@ -0,0 +1,80 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/zac-garby/fps/level"
+)
+
+// boxLevel builds a size x size level.Map bordered by walls, with an
+// open interior, for exercising castRay against a known layout.
+func boxLevel(size int) level.Map {
+	var m level.Map
+	m.Walls = make([][]level.Tile, size)
+	for y := 0; y < size; y++ {
+		m.Walls[y] = make([]level.Tile, size)
+		for x := 0; x < size; x++ {
+			if x == 0 || y == 0 || x == size-1 || y == size-1 {
+				m.Walls[y][x] = 1
+			}
+		}
+	}
+	return m
+}
+
+func TestCastRayAxisAligned(t *testing.T) {
+	lvl := boxLevel(5)
+
+	// Starting exactly on a grid line (e.g. y = 2.0) is a degenerate case
+	// where the ray is already at a cell boundary in the axis it isn't
+	// travelling along, so tests start mid-cell instead.
+	const start = 2.5
+
+	tests := []struct {
+		name     string
+		xd, yd   float64
+		wantDist float64
+		wantSide int
+		wantMapX int
+		wantMapY int
+	}{
+		{name: "pure +X hits the east wall", xd: 1, yd: 0, wantDist: 1.5, wantSide: 0, wantMapX: 4, wantMapY: 2},
+		{name: "pure -X hits the west wall", xd: -1, yd: 0, wantDist: 1.5, wantSide: 0, wantMapX: 0, wantMapY: 2},
+		{name: "pure +Y hits the south wall", xd: 0, yd: 1, wantDist: 1.5, wantSide: 1, wantMapX: 2, wantMapY: 4},
+		{name: "pure -Y hits the north wall", xd: 0, yd: -1, wantDist: 1.5, wantSide: 1, wantMapX: 2, wantMapY: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dist, _, mapX, mapY, side, hit := castRay(lvl, start, start, tt.xd, tt.yd)
+			if !hit {
+				t.Fatalf("castRay(%v, %v) did not hit anything", tt.xd, tt.yd)
+			}
+			if math.Abs(dist-tt.wantDist) > 1e-9 {
+				t.Errorf("dist = %v, want %v", dist, tt.wantDist)
+			}
+			if side != tt.wantSide {
+				t.Errorf("side = %v, want %v", side, tt.wantSide)
+			}
+			if mapX != tt.wantMapX || mapY != tt.wantMapY {
+				t.Errorf("hit cell = (%d, %d), want (%d, %d)", mapX, mapY, tt.wantMapX, tt.wantMapY)
+			}
+		})
+	}
+}
+
+func TestCastRayMissLeavesMap(t *testing.T) {
+	// A level with no walls at all: every ray runs off the edge of the
+	// grid instead of ever finding lvl.IsWall true.
+	lvl := level.Map{Walls: [][]level.Tile{
+		{0, 0, 0},
+		{0, 0, 0},
+		{0, 0, 0},
+	}}
+
+	_, _, _, _, _, hit := castRay(lvl, 1, 1, 1, 0)
+	if hit {
+		t.Error("castRay reported a hit in a level with no walls")
+	}
+}
@@ -0,0 +1,137 @@
+// Package pathing finds routes for AI-controlled entities across a level
+// using A* search over its wall grid.
+package pathing
+
+import (
+	"math"
+
+	"github.com/beefsack/go-astar"
+
+	"github.com/zac-garby/fps/level"
+)
+
+const (
+	cardinalCost = 1.0
+	diagonalCost = math.Sqrt2
+)
+
+// Vec2 is a point in tile space.
+type Vec2 struct {
+	X, Y float64
+}
+
+// PathTile is a single grid cell, walkable or not, that knows its
+// 8-directional neighbours for astar.Pather.
+type PathTile struct {
+	X, Y     int
+	Walkable bool
+	grid     *PathGrid
+}
+
+// PathGrid is a walkability grid built from a level.Map, ready to run A*
+// searches over.
+type PathGrid struct {
+	tiles [][]*PathTile
+	w, h  int
+}
+
+// BuildPathGrid converts a level's wall layer into a grid of PathTile
+// nodes: walkable wherever the level has no wall, blocked where it does.
+func BuildPathGrid(lvl level.Map) *PathGrid {
+	g := &PathGrid{w: lvl.Width(), h: lvl.Height()}
+	g.tiles = make([][]*PathTile, g.h)
+
+	for y := 0; y < g.h; y++ {
+		g.tiles[y] = make([]*PathTile, g.w)
+		for x := 0; x < g.w; x++ {
+			g.tiles[y][x] = &PathTile{X: x, Y: y, Walkable: !lvl.IsWall(x, y), grid: g}
+		}
+	}
+
+	return g
+}
+
+func (g *PathGrid) at(x, y int) *PathTile {
+	if y < 0 || y >= g.h || x < 0 || x >= g.w {
+		return nil
+	}
+	return g.tiles[y][x]
+}
+
+// canCutCorner reports whether a diagonal step from (x, y) by (dx, dy)
+// would clip through the corner of two adjacent walls, which we disallow
+// so an entity can't slip between them.
+func (g *PathGrid) canCutCorner(x, y, dx, dy int) bool {
+	alongX := g.at(x+dx, y)
+	alongY := g.at(x, y+dy)
+	return alongX != nil && alongX.Walkable && alongY != nil && alongY.Walkable
+}
+
+var neighborOffsets = []struct{ dx, dy int }{
+	{0, -1}, {0, 1}, {-1, 0}, {1, 0},
+	{-1, -1}, {1, -1}, {-1, 1}, {1, 1},
+}
+
+// PathNeighbors implements astar.Pather.
+func (t *PathTile) PathNeighbors() []astar.Pather {
+	var neighbors []astar.Pather
+
+	for _, o := range neighborOffsets {
+		n := t.grid.at(t.X+o.dx, t.Y+o.dy)
+		if n == nil || !n.Walkable {
+			continue
+		}
+
+		if o.dx != 0 && o.dy != 0 && !t.grid.canCutCorner(t.X, t.Y, o.dx, o.dy) {
+			continue
+		}
+
+		neighbors = append(neighbors, n)
+	}
+
+	return neighbors
+}
+
+// PathNeighborCost implements astar.Pather: cardinal moves cost 1,
+// diagonal moves cost √2.
+func (t *PathTile) PathNeighborCost(to astar.Pather) float64 {
+	n := to.(*PathTile)
+	if t.X != n.X && t.Y != n.Y {
+		return diagonalCost
+	}
+	return cardinalCost
+}
+
+// PathEstimatedCost implements astar.Pather using straight-line distance
+// as the heuristic.
+func (t *PathTile) PathEstimatedCost(to astar.Pather) float64 {
+	n := to.(*PathTile)
+	dx := float64(t.X - n.X)
+	dy := float64(t.Y - n.Y)
+	return math.Sqrt(dx*dx + dy*dy)
+}
+
+// FindPath searches for a route between two points in tile space. It
+// returns the waypoints from start to destination (tile centres), the
+// total path cost, and whether a path was found at all.
+func (g *PathGrid) FindPath(sx, sy, dx, dy float64) ([]Vec2, float64, bool) {
+	start := g.at(int(sx), int(sy))
+	dest := g.at(int(dx), int(dy))
+	if start == nil || dest == nil || !start.Walkable || !dest.Walkable {
+		return nil, 0, false
+	}
+
+	path, cost, found := astar.Path(start, dest)
+	if !found {
+		return nil, 0, false
+	}
+
+	// astar.Path returns the path from dest back to start; reverse it.
+	waypoints := make([]Vec2, len(path))
+	for i, p := range path {
+		t := p.(*PathTile)
+		waypoints[len(path)-1-i] = Vec2{X: float64(t.X) + 0.5, Y: float64(t.Y) + 0.5}
+	}
+
+	return waypoints, cost, true
+}
@@ -0,0 +1,99 @@
+package pathing
+
+import (
+	"testing"
+
+	"github.com/zac-garby/fps/level"
+)
+
+// gridFromRows builds a PathGrid from rows of '#' (wall) and '.' (open)
+// characters, so tests can describe a layout visually.
+func gridFromRows(rows []string) *PathGrid {
+	var m level.Map
+	m.Walls = make([][]level.Tile, len(rows))
+	for y, row := range rows {
+		m.Walls[y] = make([]level.Tile, len(row))
+		for x, c := range row {
+			if c == '#' {
+				m.Walls[y][x] = 1
+			}
+		}
+	}
+	return BuildPathGrid(m)
+}
+
+func TestCanCutCorner(t *testing.T) {
+	tests := []struct {
+		name         string
+		rows         []string
+		x, y, dx, dy int
+		want         bool
+	}{
+		{
+			name: "open corner allows the cut",
+			rows: []string{
+				"...",
+				"...",
+				"...",
+			},
+			x: 1, y: 1, dx: 1, dy: 1,
+			want: true,
+		},
+		{
+			name: "wall along the x side blocks the cut",
+			rows: []string{
+				"...",
+				"..#",
+				"...",
+			},
+			x: 1, y: 1, dx: 1, dy: 1,
+			want: false,
+		},
+		{
+			name: "wall along the y side blocks the cut",
+			rows: []string{
+				"...",
+				"...",
+				".#.",
+			},
+			x: 1, y: 1, dx: 1, dy: 1,
+			want: false,
+		},
+		{
+			name: "stepping off the grid blocks the cut",
+			rows: []string{
+				"...",
+				"...",
+				"...",
+			},
+			x: 2, y: 2, dx: 1, dy: 1,
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gridFromRows(tt.rows)
+			if got := g.canCutCorner(tt.x, tt.y, tt.dx, tt.dy); got != tt.want {
+				t.Errorf("canCutCorner(%d, %d, %d, %d) = %v, want %v", tt.x, tt.y, tt.dx, tt.dy, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathNeighborsExcludesCutCorners(t *testing.T) {
+	g := gridFromRows([]string{
+		"...",
+		"..#",
+		"...",
+	})
+
+	neighbors := g.at(1, 1).PathNeighbors()
+
+	for _, n := range neighbors {
+		tile := n.(*PathTile)
+		if tile.X == 2 && tile.Y == 2 {
+			t.Errorf("PathNeighbors included (2, 2), a diagonal cut through the wall at (2, 1)")
+		}
+	}
+}
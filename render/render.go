@@ -0,0 +1,69 @@
+// Package render decouples game logic from SDL's renderer calls. SDL
+// requires every call into a given renderer to happen on the OS thread
+// that created it, so simulation and AI code that runs elsewhere can't
+// call the renderer directly — instead they push draw commands onto a
+// queue here, and the OS-locked render thread flushes it with Purge.
+package render
+
+import (
+	"sync"
+
+	"github.com/veandco/go-sdl2/sdl"
+
+	"github.com/zac-garby/fps/texture"
+)
+
+var (
+	mu       sync.Mutex
+	renderer *sdl.Renderer
+	queue    []func(*sdl.Renderer)
+)
+
+// Init binds the queue to the renderer that owns the OS-locked render
+// thread. It must be called once, from that thread, before any Queue or
+// Purge call.
+func Init(r *sdl.Renderer) {
+	renderer = r
+}
+
+// Queue schedules fn to run against the bound renderer on the next call
+// to Purge. Safe to call from any goroutine.
+func Queue(fn func(*sdl.Renderer)) {
+	mu.Lock()
+	queue = append(queue, fn)
+	mu.Unlock()
+}
+
+// Purge runs every command queued since the last Purge, in the order
+// they were queued, against the bound renderer. It must be called from
+// the OS-locked render thread.
+func Purge() {
+	mu.Lock()
+	pending := queue
+	queue = nil
+	mu.Unlock()
+
+	for _, fn := range pending {
+		fn(renderer)
+	}
+}
+
+// Copy queues a texture copy and, at the moment it actually runs,
+// refreshes name's last-used time in mgr and applies brightness as a
+// color mod — so a texture drawn every frame is never reaped by the
+// Scavenger for going idle, and the color mod it draws with is always
+// the one computed for this call.
+//
+// brightness must be set here rather than by the caller calling
+// tex.SetColorMod before queuing: tex is a single shared *sdl.Texture,
+// and many columns in a frame can share the same one (e.g. every "wall1"
+// slice), so a SetColorMod made immediately on the simulation goroutine
+// would just get overwritten by the next column's call before Purge ever
+// runs the earlier one's queued Copy.
+func Copy(mgr *texture.Manager, name string, tex *sdl.Texture, src, dest *sdl.Rect, brightness uint8) {
+	Queue(func(r *sdl.Renderer) {
+		mgr.Touch(name)
+		tex.SetColorMod(brightness, brightness, brightness)
+		r.Copy(tex, src, dest)
+	})
+}
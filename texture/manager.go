@@ -0,0 +1,198 @@
+// Package texture loads SDL textures from disk on demand and frees the
+// ones that fall idle, so the engine can afford hundreds of wall and
+// entity textures across large levels without exhausting VRAM.
+package texture
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/veandco/go-sdl2/img"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// idleTTL is how long an unreferenced texture may sit untouched before
+// the Scavenger destroys it.
+const idleTTL = 10 * time.Second
+
+type entry struct {
+	tex      *sdl.Texture
+	refs     int
+	lastUsed time.Time
+}
+
+// Manager loads textures from assets/<name>.png the first time they're
+// needed, keeps explicitly-loaded ones alive for as long as they're
+// referenced, and lets everything else expire once it goes unused.
+//
+// Every SDL call a Manager makes — loading a texture and destroying one —
+// is dispatched through queue rather than issued directly, because only
+// the OS-locked render thread that owns renderer is allowed to touch it.
+// Load and Get are called from goroutines that aren't that thread, so
+// queue is normally render.Queue.
+type Manager struct {
+	renderer *sdl.Renderer
+	queue    func(func(*sdl.Renderer))
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// NewManager creates a Manager that loads textures through renderer,
+// dispatching every renderer-bound call through queue so it runs on the
+// thread that owns renderer.
+func NewManager(renderer *sdl.Renderer, queue func(func(*sdl.Renderer))) *Manager {
+	return &Manager{renderer: renderer, queue: queue, entries: make(map[string]*entry)}
+}
+
+// Load fetches name, loading it from disk on first use, and increments
+// its reference count so the Scavenger will never destroy it until a
+// matching Release. Use this for textures that must outlive a single
+// frame, such as the HUD weapon sprite.
+//
+// Load is only ever called during setup, on the render thread itself
+// before it starts pumping the queue, so it loads directly rather than
+// dispatching through queue — queuing here would just deadlock waiting
+// for a Purge that hasn't started yet.
+func (m *Manager) Load(name string) (*sdl.Texture, error) {
+	m.mu.Lock()
+	if e, ok := m.entries[name]; ok {
+		e.refs++
+		e.lastUsed = time.Now()
+		tex := e.tex
+		m.mu.Unlock()
+		return tex, nil
+	}
+	m.mu.Unlock()
+
+	tex, err := m.loadFile(m.renderer, name)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.entries[name] = &entry{tex: tex, refs: 1, lastUsed: time.Now()}
+	m.mu.Unlock()
+
+	return tex, nil
+}
+
+// Get returns name without taking a reference, loading it from disk
+// first if it isn't cached (either because it was never fetched, or
+// because the Scavenger has since freed it). Textures fetched this way
+// live only as long as something keeps calling Get or render.Copy
+// touches them before the TTL elapses.
+//
+// Get is called from the simulation goroutine, not the render thread, so
+// a cache-miss load is dispatched through queue and waited on rather than
+// run directly against m.renderer.
+func (m *Manager) Get(name string) (*sdl.Texture, error) {
+	m.mu.Lock()
+	e, ok := m.entries[name]
+	m.mu.Unlock()
+
+	if ok {
+		m.Touch(name)
+		return e.tex, nil
+	}
+
+	tex, err := m.loadQueued(name)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.entries[name] = &entry{tex: tex, lastUsed: time.Now()}
+	m.mu.Unlock()
+
+	return tex, nil
+}
+
+// Release drops one reference taken by Load. The texture isn't destroyed
+// immediately; it's just left for the Scavenger to reap once it's both
+// unreferenced and idle.
+func (m *Manager) Release(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.entries[name]; ok && e.refs > 0 {
+		e.refs--
+	}
+}
+
+// Touch marks name as accessed just now. render calls this on every
+// Copy, so a texture drawn every frame never goes idle even if nothing
+// holds an explicit reference to it.
+func (m *Manager) Touch(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.entries[name]; ok {
+		e.lastUsed = time.Now()
+	}
+}
+
+// Scavenger starts a goroutine that wakes every interval and destroys
+// any texture that is both unreferenced and has been idle longer than
+// the TTL.
+func (m *Manager) Scavenger(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			m.scavenge()
+		}
+	}()
+}
+
+// scavenge runs on the Scavenger's own ticker goroutine, so the actual
+// Destroy is queued rather than called directly: a render.Copy closure
+// built moments earlier in the simulation goroutine may still be sitting
+// in the queue holding this same texture, and queuing Destroy behind it
+// guarantees it only runs once every earlier-queued use has been drawn.
+func (m *Manager) scavenge() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for name, e := range m.entries {
+		if e.refs == 0 && time.Since(e.lastUsed) > idleTTL {
+			tex := e.tex
+			m.queue(func(*sdl.Renderer) {
+				tex.Destroy()
+			})
+			delete(m.entries, name)
+		}
+	}
+}
+
+// loadFile loads name against r directly. Callers already on the render
+// thread (Load) may call this straight away; anyone else must go through
+// loadQueued instead.
+func (m *Manager) loadFile(r *sdl.Renderer, name string) (*sdl.Texture, error) {
+	tex, err := img.LoadTexture(r, fmt.Sprintf("assets/%s.png", name))
+	if err != nil {
+		return nil, fmt.Errorf("texture: load %s: %w", name, err)
+	}
+	return tex, nil
+}
+
+// loadQueued loads name on the render thread via m.queue and blocks until
+// the load completes, so a cache miss reached from any goroutine other
+// than the render thread never touches the renderer itself.
+func (m *Manager) loadQueued(name string) (*sdl.Texture, error) {
+	type result struct {
+		tex *sdl.Texture
+		err error
+	}
+
+	done := make(chan result, 1)
+	m.queue(func(r *sdl.Renderer) {
+		tex, err := m.loadFile(r, name)
+		done <- result{tex: tex, err: err}
+	})
+
+	res := <-done
+	return res.tex, res.err
+}